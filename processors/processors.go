@@ -0,0 +1,130 @@
+// Package processors provides ready-made zlogsentry.EventProcessors for
+// common pipeline stages: scrubbing sensitive values, rate-limiting by
+// fingerprint, and sampling by level.
+package processors
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Scrub returns an EventProcessor that replaces any substring of
+// event.Message or event.Extra's string values matching pattern with
+// replacement. It's meant for redacting PII (emails, tokens, card numbers)
+// before an event leaves the process.
+func Scrub(pattern *regexp.Regexp, replacement string) func(*sentry.Event, *sentry.EventHint) *sentry.Event {
+	return func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		event.Message = pattern.ReplaceAllString(event.Message, replacement)
+
+		for key, value := range event.Extra {
+			if s, ok := value.(string); ok {
+				event.Extra[key] = pattern.ReplaceAllString(s, replacement)
+			}
+		}
+
+		return event
+	}
+}
+
+// FingerprintLimiter rate-limits events by fingerprint using a token bucket
+// per distinct fingerprint, so a single repeating error can't flood Sentry.
+// Events without a fingerprint are grouped under the message instead.
+type FingerprintLimiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // bucket capacity
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+// NewFingerprintLimiter creates a FingerprintLimiter that allows, per
+// fingerprint, an initial burst of burst events followed by a sustained rate
+// of rate events per second.
+func NewFingerprintLimiter(rate float64, burst int) *FingerprintLimiter {
+	return &FingerprintLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Process implements the zlogsentry.EventProcessor shape. It returns nil,
+// suppressing the event, once the fingerprint's bucket is exhausted.
+func (l *FingerprintLimiter) Process(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+	key := fingerprintKey(event)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	now := l.now()
+	if !ok {
+		b = &bucket{tokens: l.burst, lastFill: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.lastFill).Seconds()
+	b.tokens = minFloat(l.burst, b.tokens+elapsed*l.rate)
+	b.lastFill = now
+
+	if b.tokens < 1 {
+		return nil
+	}
+
+	b.tokens--
+	return event
+}
+
+func fingerprintKey(event *sentry.Event) string {
+	if len(event.Fingerprint) > 0 {
+		return fmt.Sprintf("%v", event.Fingerprint)
+	}
+	return event.Message
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// LevelSampler drops a configurable fraction of events per Sentry level,
+// e.g. to keep only 10% of warnings while always keeping errors.
+type LevelSampler struct {
+	rates map[sentry.Level]float64
+	rand  func() float64
+}
+
+// NewLevelSampler creates a LevelSampler. rates maps a sentry.Level to the
+// fraction of its events (0.0-1.0) that should be kept; levels absent from
+// rates are always kept.
+func NewLevelSampler(rates map[sentry.Level]float64) *LevelSampler {
+	return &LevelSampler{rates: rates, rand: rand.Float64}
+}
+
+// Process implements the zlogsentry.EventProcessor shape.
+func (s *LevelSampler) Process(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+	rate, ok := s.rates[event.Level]
+	if !ok || rate >= 1 {
+		return event
+	}
+
+	if rate <= 0 || s.rand() >= rate {
+		return nil
+	}
+
+	return event
+}
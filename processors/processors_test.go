@@ -0,0 +1,94 @@
+package processors
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrub(t *testing.T) {
+	pattern := regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`)
+	scrub := Scrub(pattern, "[redacted]")
+
+	event := &sentry.Event{
+		Message: "charged card 4242-4242-4242-4242",
+		Extra:   map[string]interface{}{"card": "4242-4242-4242-4242", "count": 1},
+	}
+
+	result := scrub(event, &sentry.EventHint{})
+
+	assert.Equal(t, "charged card [redacted]", result.Message)
+	assert.Equal(t, "[redacted]", result.Extra["card"])
+	assert.Equal(t, 1, result.Extra["count"])
+}
+
+func TestFingerprintLimiter_AllowsBurstThenLimits(t *testing.T) {
+	limiter := NewFingerprintLimiter(1, 2)
+	ts := time.Now()
+	limiter.now = func() time.Time { return ts }
+
+	event := &sentry.Event{Fingerprint: []string{"db", "timeout"}}
+
+	assert.NotNil(t, limiter.Process(event, nil))
+	assert.NotNil(t, limiter.Process(event, nil))
+	assert.Nil(t, limiter.Process(event, nil), "third event within the same instant should exceed the burst")
+}
+
+func TestFingerprintLimiter_RefillsOverTime(t *testing.T) {
+	limiter := NewFingerprintLimiter(1, 1)
+	ts := time.Now()
+	limiter.now = func() time.Time { return ts }
+
+	event := &sentry.Event{Fingerprint: []string{"db"}}
+
+	assert.NotNil(t, limiter.Process(event, nil))
+	assert.Nil(t, limiter.Process(event, nil))
+
+	ts = ts.Add(2 * time.Second)
+	assert.NotNil(t, limiter.Process(event, nil), "bucket should have refilled after 2s at a 1/s rate")
+}
+
+func TestFingerprintLimiter_DistinctFingerprintsDontShareBudget(t *testing.T) {
+	limiter := NewFingerprintLimiter(1, 1)
+
+	a := &sentry.Event{Fingerprint: []string{"db"}}
+	b := &sentry.Event{Fingerprint: []string{"network"}}
+
+	assert.NotNil(t, limiter.Process(a, nil))
+	assert.NotNil(t, limiter.Process(b, nil))
+}
+
+func TestLevelSampler_KeepsUnlistedLevels(t *testing.T) {
+	sampler := NewLevelSampler(map[sentry.Level]float64{sentry.LevelWarning: 0})
+
+	event := &sentry.Event{Level: sentry.LevelError}
+	assert.NotNil(t, sampler.Process(event, nil))
+}
+
+func TestLevelSampler_DropsZeroRate(t *testing.T) {
+	sampler := NewLevelSampler(map[sentry.Level]float64{sentry.LevelWarning: 0})
+
+	event := &sentry.Event{Level: sentry.LevelWarning}
+	assert.Nil(t, sampler.Process(event, nil))
+}
+
+func TestLevelSampler_KeepsFullRate(t *testing.T) {
+	sampler := NewLevelSampler(map[sentry.Level]float64{sentry.LevelError: 1})
+
+	event := &sentry.Event{Level: sentry.LevelError}
+	assert.NotNil(t, sampler.Process(event, nil))
+}
+
+func TestLevelSampler_PartialRateUsesRand(t *testing.T) {
+	sampler := NewLevelSampler(map[sentry.Level]float64{sentry.LevelWarning: 0.5})
+	sampler.rand = func() float64 { return 0.4 }
+
+	event := &sentry.Event{Level: sentry.LevelWarning}
+	assert.NotNil(t, sampler.Process(event, nil))
+
+	sampler.rand = func() float64 { return 0.6 }
+	assert.Nil(t, sampler.Process(event, nil))
+}
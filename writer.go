@@ -0,0 +1,984 @@
+// Package zlogsentry provides a zerolog.LevelWriter implementation that
+// forwards log events to Sentry.
+package zlogsentry
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultFlushTimeout = 3 * time.Second
+
+	levelFieldName   = "level"
+	messageFieldName = "message"
+	errorFieldName   = "error"
+	timeFieldName    = "time"
+
+	// fingerprintSeparator splits a fingerprint field's string value into
+	// Sentry's []string fingerprint.
+	fingerprintSeparator = "|"
+)
+
+// Default field names used by AttachContext. parseLogEvent routes trace
+// context only from the field names passed to WithTraceContext, so a Writer
+// reading AttachContext's output passes these names to it.
+const (
+	DefaultTraceIDField      = "trace_id"
+	DefaultSpanIDField       = "span_id"
+	DefaultParentSpanIDField = "parent_span_id"
+	DefaultTraceOpField      = "trace_op"
+	DefaultTraceStatusField  = "trace_status"
+)
+
+// now is overridable in tests.
+var now = time.Now
+
+// levelsMapping maps zerolog levels onto their Sentry equivalents.
+var levelsMapping = map[zerolog.Level]sentry.Level{
+	zerolog.DebugLevel: sentry.LevelDebug,
+	zerolog.InfoLevel:  sentry.LevelInfo,
+	zerolog.WarnLevel:  sentry.LevelWarning,
+	zerolog.ErrorLevel: sentry.LevelError,
+	zerolog.FatalLevel: sentry.LevelFatal,
+	zerolog.PanicLevel: sentry.LevelFatal,
+}
+
+// defaultLevels lists the zerolog levels that are forwarded to Sentry when
+// WithLevels has not been used to override the set.
+var defaultLevels = []zerolog.Level{
+	zerolog.ErrorLevel,
+	zerolog.FatalLevel,
+	zerolog.PanicLevel,
+}
+
+// DropPolicy determines what happens to an event when the async queue is
+// full.
+type DropPolicy int
+
+const (
+	// DropNewest discards the event that just failed to enqueue, leaving the
+	// queue untouched.
+	DropNewest DropPolicy = iota
+	// DropOldest discards the oldest queued event to make room for the one
+	// that just failed to enqueue.
+	DropOldest
+)
+
+// AsyncStats reports cumulative counters for a Writer running in async mode.
+type AsyncStats struct {
+	Enqueued uint64
+	Dropped  uint64
+	Sent     uint64
+	Failed   uint64
+}
+
+// asyncState holds everything needed to run the bounded worker pool. It is
+// nil unless WithAsync was used.
+type asyncState struct {
+	queue   chan *sentry.Event
+	policy  DropPolicy
+	workers int
+
+	// mu guards closed: Close takes it exclusively while closing queue, and
+	// enqueueAsync holds it for reading while it sends, so a send can never
+	// race a close of the same channel.
+	mu     sync.RWMutex
+	closed bool
+
+	wg sync.WaitGroup
+
+	enqueued uint64
+	dropped  uint64
+	sent     uint64
+	failed   uint64
+}
+
+// Writer implements zerolog.LevelWriter, forwarding log events to Sentry
+// through a sentry.Hub.
+type Writer struct {
+	hub *sentry.Hub
+
+	levels          map[zerolog.Level]struct{}
+	withBreadcrumbs bool
+	flushTimeout    time.Duration
+
+	async *asyncState
+
+	tagFields         map[string]struct{}
+	fingerprintField  string
+	transactionField  string
+	userIDField       string
+	userEmailField    string
+	userUsernameField string
+	stackField        string
+
+	traceIDField      string
+	spanIDField       string
+	parentSpanIDField string
+	traceParentField  string
+	traceOpField      string
+	traceStatusField  string
+
+	eventProcessors []EventProcessor
+}
+
+// EventProcessor transforms or suppresses a parsed event before it reaches
+// the Sentry hub. It has the same shape as sentry.ClientOptions.BeforeSend so
+// the two compose: a Writer's processors run first, in registration order,
+// and whatever they produce is then handed to the hub, which applies
+// WithBeforeSend itself. Returning nil suppresses the event.
+type EventProcessor func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event
+
+// Option configures a Writer and, where applicable, the sentry.ClientOptions
+// used to build its underlying hub.
+type Option func(w *Writer, o *sentry.ClientOptions)
+
+// New creates a Writer that reports events to the Sentry project identified
+// by dsn. Passing an empty dsn disables delivery but otherwise behaves as if
+// pointed at a real project, which is convenient for tests.
+func New(dsn string, options ...Option) (*Writer, error) {
+	so := sentry.ClientOptions{Dsn: dsn}
+
+	w := &Writer{
+		levels:       levelSet(defaultLevels),
+		flushTimeout: defaultFlushTimeout,
+	}
+
+	for _, opt := range options {
+		opt(w, &so)
+	}
+
+	client, err := sentry.NewClient(so)
+	if err != nil {
+		return nil, err
+	}
+
+	w.hub = sentry.NewHub(client, sentry.NewScope())
+	w.startAsyncWorkers()
+
+	return w, nil
+}
+
+// NewWithHub creates a Writer that reports events through an existing
+// sentry.Hub, e.g. one already configured and shared by the rest of the
+// application.
+func NewWithHub(hub *sentry.Hub, options ...Option) (*Writer, error) {
+	if hub == nil {
+		return nil, errors.New("hub cannot be nil")
+	}
+
+	w := &Writer{
+		hub:          hub,
+		levels:       levelSet(defaultLevels),
+		flushTimeout: defaultFlushTimeout,
+	}
+
+	// hub already has a client, so Options that configure sentry.ClientOptions
+	// (e.g. WithSampleRate) have no effect here; only Writer-level options
+	// (e.g. WithFlushTimeout, WithLevels) apply.
+	var discarded sentry.ClientOptions
+	for _, opt := range options {
+		opt(w, &discarded)
+	}
+
+	w.startAsyncWorkers()
+
+	return w, nil
+}
+
+func levelSet(levels []zerolog.Level) map[zerolog.Level]struct{} {
+	m := make(map[zerolog.Level]struct{}, len(levels))
+	for _, l := range levels {
+		m[l] = struct{}{}
+	}
+	return m
+}
+
+// Write implements io.Writer. The level is parsed out of the JSON payload
+// itself, which is what zerolog does when the writer isn't used through
+// WriteLevel (e.g. when wrapped in an io.MultiWriter).
+func (w *Writer) Write(data []byte) (int, error) {
+	level, err := w.parseLogLevel(data)
+	if err != nil {
+		return len(data), nil
+	}
+
+	return w.WriteLevel(level, data)
+}
+
+// WriteLevel implements zerolog.LevelWriter.
+func (w *Writer) WriteLevel(level zerolog.Level, data []byte) (int, error) {
+	if _, enabled := w.levels[level]; !enabled {
+		return len(data), nil
+	}
+
+	event, ok := w.parseLogEvent(data)
+	if !ok {
+		return len(data), nil
+	}
+
+	event.Level = levelsMapping[level]
+
+	if len(w.eventProcessors) > 0 {
+		event = w.runEventProcessors(event)
+		if event == nil {
+			return len(data), nil
+		}
+	}
+
+	if w.withBreadcrumbs {
+		w.addBreadcrumb(event)
+	}
+
+	if w.async != nil {
+		w.enqueueAsync(event)
+	} else {
+		w.hub.CaptureEvent(event)
+	}
+
+	return len(data), nil
+}
+
+// runEventProcessors runs event through the writer's registered
+// EventProcessors in registration order, short-circuiting as soon as one
+// returns nil.
+func (w *Writer) runEventProcessors(event *sentry.Event) *sentry.Event {
+	hint := &sentry.EventHint{}
+
+	for _, process := range w.eventProcessors {
+		event = process(event, hint)
+		if event == nil {
+			return nil
+		}
+	}
+
+	return event
+}
+
+// enqueueAsync places event on the async queue, applying the configured
+// DropPolicy when the queue is full.
+func (w *Writer) enqueueAsync(event *sentry.Event) {
+	a := w.async
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		atomic.AddUint64(&a.dropped, 1)
+		return
+	}
+
+	select {
+	case a.queue <- event:
+		atomic.AddUint64(&a.enqueued, 1)
+		return
+	default:
+	}
+
+	switch a.policy {
+	case DropOldest:
+		select {
+		case <-a.queue:
+			atomic.AddUint64(&a.dropped, 1)
+		default:
+		}
+
+		select {
+		case a.queue <- event:
+			atomic.AddUint64(&a.enqueued, 1)
+		default:
+			atomic.AddUint64(&a.dropped, 1)
+		}
+	default: // DropNewest
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+// AsyncStats returns the current enqueued/dropped/sent/failed counters. It
+// returns the zero value when the writer isn't running in async mode.
+func (w *Writer) AsyncStats() AsyncStats {
+	if w.async == nil {
+		return AsyncStats{}
+	}
+
+	return AsyncStats{
+		Enqueued: atomic.LoadUint64(&w.async.enqueued),
+		Dropped:  atomic.LoadUint64(&w.async.dropped),
+		Sent:     atomic.LoadUint64(&w.async.sent),
+		Failed:   atomic.LoadUint64(&w.async.failed),
+	}
+}
+
+// addBreadcrumb records event as a breadcrumb on the writer's scope, so that
+// it shows up on whatever event is eventually captured.
+func (w *Writer) addBreadcrumb(event *sentry.Event) {
+	category, _ := event.Extra["category"].(string)
+
+	w.hub.AddBreadcrumb(&sentry.Breadcrumb{
+		Category: category,
+		Message:  event.Message,
+		Level:    event.Level,
+		Type:     string(event.Level),
+		Data:     event.Extra,
+	}, nil)
+}
+
+// Close stops any async workers, waiting for them to drain the queue, and
+// then flushes buffered events to Sentry, waiting up to w.flushTimeout
+// (defaultFlushTimeout unless overridden with WithFlushTimeout) for the flush
+// to complete. Draining the queue is not bounded by w.flushTimeout.
+func (w *Writer) Close() error {
+	if w.async != nil {
+		w.async.mu.Lock()
+		w.async.closed = true
+		close(w.async.queue)
+		w.async.mu.Unlock()
+
+		w.async.wg.Wait()
+	}
+
+	w.hub.Flush(w.flushTimeout)
+	return nil
+}
+
+// runAsyncWorker drains the async queue, calling hub.CaptureEvent for each
+// event, until the queue is closed and emptied.
+func (w *Writer) runAsyncWorker() {
+	defer w.async.wg.Done()
+
+	for event := range w.async.queue {
+		id := w.hub.CaptureEvent(event)
+		if id != nil {
+			atomic.AddUint64(&w.async.sent, 1)
+		} else {
+			atomic.AddUint64(&w.async.failed, 1)
+		}
+	}
+}
+
+// parseLogLevel extracts and parses the "level" field of a zerolog JSON
+// payload.
+func (w *Writer) parseLogLevel(data []byte) (zerolog.Level, error) {
+	var event struct {
+		Level string `json:"level"`
+	}
+
+	if err := json.Unmarshal(data, &event); err != nil {
+		return zerolog.NoLevel, err
+	}
+
+	return zerolog.ParseLevel(event.Level)
+}
+
+// parseLogEvent turns a zerolog JSON payload into a *sentry.Event. Fields
+// named via WithTagFields, WithFingerprintField, WithTransactionField,
+// WithUserFields, WithStackField, WithTraceContext or WithTraceParentField
+// are routed to their Sentry destinations; any other field that isn't one of
+// the well-known zerolog fields is copied into event.Extra. ok is false when
+// data isn't valid JSON.
+func (w *Writer) parseLogEvent(data []byte) (*sentry.Event, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false
+	}
+
+	event := sentry.NewEvent()
+	event.Timestamp = now()
+	event.Logger = "zerolog"
+	event.Extra = make(map[string]interface{}, len(fields))
+
+	var (
+		stackValue                             interface{}
+		hasStack                               bool
+		traceIDStr, spanIDStr, parentSpanIDStr string
+		traceParentStr                         string
+		traceOpStr, traceStatusStr             string
+	)
+
+	for field, value := range fields {
+		switch {
+		case field == levelFieldName || field == timeFieldName:
+			// already handled elsewhere
+		case field == messageFieldName:
+			if msg, ok := value.(string); ok {
+				event.Message = msg
+			}
+		case field == errorFieldName:
+			if msg, ok := value.(string); ok {
+				event.Exception = append(event.Exception, sentry.Exception{Value: msg})
+			}
+		case w.stackField != "" && field == w.stackField:
+			stackValue, hasStack = value, true
+		case w.fingerprintField != "" && field == w.fingerprintField:
+			if s, ok := value.(string); ok {
+				event.Fingerprint = strings.Split(s, fingerprintSeparator)
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.transactionField != "" && field == w.transactionField:
+			if s, ok := value.(string); ok {
+				event.Transaction = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.userIDField != "" && field == w.userIDField:
+			if s, ok := stringifyField(value); ok {
+				event.User.ID = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.userEmailField != "" && field == w.userEmailField:
+			if s, ok := value.(string); ok {
+				event.User.Email = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.userUsernameField != "" && field == w.userUsernameField:
+			if s, ok := value.(string); ok {
+				event.User.Username = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.traceIDField != "" && field == w.traceIDField:
+			if s, ok := value.(string); ok {
+				traceIDStr = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.spanIDField != "" && field == w.spanIDField:
+			if s, ok := value.(string); ok {
+				spanIDStr = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.parentSpanIDField != "" && field == w.parentSpanIDField:
+			if s, ok := value.(string); ok {
+				parentSpanIDStr = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.traceParentField != "" && field == w.traceParentField:
+			if s, ok := value.(string); ok {
+				traceParentStr = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.traceOpField != "" && field == w.traceOpField:
+			if s, ok := value.(string); ok {
+				traceOpStr = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.traceStatusField != "" && field == w.traceStatusField:
+			if s, ok := value.(string); ok {
+				traceStatusStr = s
+			} else {
+				addExtra(event, field, value)
+			}
+		case w.isTagField(field):
+			if s, ok := stringifyField(value); ok {
+				if event.Tags == nil {
+					event.Tags = make(map[string]string, 1)
+				}
+				event.Tags[field] = s
+			} else {
+				addExtra(event, field, value)
+			}
+		default:
+			addExtra(event, field, value)
+		}
+	}
+
+	if hasStack && !applyStackField(event, stackValue) {
+		addExtra(event, w.stackField, stackValue)
+	}
+
+	if traceIDStr != "" || spanIDStr != "" || traceParentStr != "" {
+		applyTraceContext(event, traceIDStr, spanIDStr, parentSpanIDStr, traceParentStr, traceOpStr, traceStatusStr)
+	}
+
+	return event, true
+}
+
+// applyTraceContext builds a sentry.TraceContext from the routed trace
+// fields and attaches it to event.Contexts["trace"]. A W3C traceparent
+// value, when present, takes precedence over the discrete trace/span ID
+// fields since it carries both in one value. op and status are the span's
+// operation name and a sentry.SpanStatus name (e.g. "ok", "not_found"); an
+// unrecognized status name is dropped rather than rejecting the whole
+// context.
+func applyTraceContext(event *sentry.Event, traceID, spanID, parentSpanID, traceParent, op, status string) {
+	if traceParent != "" {
+		if tid, sid, ok := parseTraceParent(traceParent); ok {
+			traceID, spanID = tid, sid
+		}
+	}
+
+	if traceID == "" && spanID == "" {
+		return
+	}
+
+	tc := sentry.TraceContext{
+		TraceID: parseTraceID(traceID),
+		SpanID:  parseSpanID(spanID),
+		Op:      op,
+	}
+	if parentSpanID != "" {
+		tc.ParentSpanID = parseSpanID(parentSpanID)
+	}
+	if ss, ok := spanStatusByName[status]; ok {
+		tc.Status = ss
+	}
+
+	// Build the context by hand rather than via tc.Map(): that method puts
+	// the raw sentry.TraceID/sentry.SpanID values (not their hex string
+	// form) under these keys, which isn't what a "trace" context is
+	// expected to contain.
+	trace := sentry.Context{
+		"trace_id": tc.TraceID.String(),
+		"span_id":  tc.SpanID.String(),
+	}
+	if parentSpanID != "" {
+		trace["parent_span_id"] = tc.ParentSpanID.String()
+	}
+	if tc.Op != "" {
+		trace["op"] = tc.Op
+	}
+	if tc.Status != sentry.SpanStatusUndefined {
+		trace["status"] = tc.Status.String()
+	}
+
+	if event.Contexts == nil {
+		event.Contexts = make(map[string]sentry.Context, 1)
+	}
+	event.Contexts["trace"] = trace
+}
+
+// spanStatusByName maps a sentry.SpanStatus's string form back onto the
+// value itself, for parsing a status name routed in from a log field.
+var spanStatusByName = map[string]sentry.SpanStatus{
+	sentry.SpanStatusOK.String():                 sentry.SpanStatusOK,
+	sentry.SpanStatusCanceled.String():           sentry.SpanStatusCanceled,
+	sentry.SpanStatusUnknown.String():            sentry.SpanStatusUnknown,
+	sentry.SpanStatusInvalidArgument.String():    sentry.SpanStatusInvalidArgument,
+	sentry.SpanStatusDeadlineExceeded.String():   sentry.SpanStatusDeadlineExceeded,
+	sentry.SpanStatusNotFound.String():           sentry.SpanStatusNotFound,
+	sentry.SpanStatusAlreadyExists.String():      sentry.SpanStatusAlreadyExists,
+	sentry.SpanStatusPermissionDenied.String():   sentry.SpanStatusPermissionDenied,
+	sentry.SpanStatusResourceExhausted.String():  sentry.SpanStatusResourceExhausted,
+	sentry.SpanStatusFailedPrecondition.String(): sentry.SpanStatusFailedPrecondition,
+	sentry.SpanStatusAborted.String():            sentry.SpanStatusAborted,
+	sentry.SpanStatusOutOfRange.String():         sentry.SpanStatusOutOfRange,
+	sentry.SpanStatusUnimplemented.String():      sentry.SpanStatusUnimplemented,
+	sentry.SpanStatusInternalError.String():      sentry.SpanStatusInternalError,
+	sentry.SpanStatusUnavailable.String():        sentry.SpanStatusUnavailable,
+	sentry.SpanStatusDataLoss.String():           sentry.SpanStatusDataLoss,
+	sentry.SpanStatusUnauthenticated.String():    sentry.SpanStatusUnauthenticated,
+}
+
+// parseTraceParent splits a W3C "traceparent" header value
+// (version-traceid-spanid-flags) into its trace and span ID components.
+func parseTraceParent(s string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+func parseTraceID(s string) sentry.TraceID {
+	var id sentry.TraceID
+	if b, err := hex.DecodeString(s); err == nil {
+		copy(id[:], b)
+	}
+	return id
+}
+
+func parseSpanID(s string) sentry.SpanID {
+	var id sentry.SpanID
+	if b, err := hex.DecodeString(s); err == nil {
+		copy(id[:], b)
+	}
+	return id
+}
+
+// isTagField reports whether field was named in WithTagFields.
+func (w *Writer) isTagField(field string) bool {
+	_, ok := w.tagFields[field]
+	return ok
+}
+
+// addExtra stores value under field in event.Extra, the same fallback every
+// unrecognized field gets. It's also used when a field is routed to a
+// special destination (tag, fingerprint, trace context, ...) but its JSON
+// value isn't the type that destination expects, so the data lands
+// somewhere instead of being silently dropped.
+func addExtra(event *sentry.Event, field string, value interface{}) {
+	if s, ok := value.(string); ok {
+		event.Extra[field] = s
+		return
+	}
+	if raw, err := json.Marshal(value); err == nil {
+		event.Extra[field] = string(raw)
+	} else {
+		event.Extra[field] = value
+	}
+}
+
+// stringifyField converts a JSON-decoded scalar into a string suitable for a
+// Sentry tag or user ID, where only strings and numbers make sense.
+func stringifyField(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// applyStackField parses zerolog pkgerrors.MarshalStack output (a slice of
+// {"func", "source", "line"} maps) into a sentry.Stacktrace and attaches it
+// to the event's first exception, or to a synthetic current thread if the
+// event has no exception. It reports whether a stacktrace was attached.
+func applyStackField(event *sentry.Event, value interface{}) bool {
+	raw, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+
+	frames := make([]sentry.Frame, 0, len(raw))
+	for _, f := range raw {
+		m, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var frame sentry.Frame
+		if fn, ok := m["func"].(string); ok {
+			frame.Function = fn
+		}
+		if src, ok := m["source"].(string); ok {
+			frame.AbsPath = src
+		}
+		if ln, ok := m["line"].(string); ok {
+			if n, err := strconv.Atoi(ln); err == nil {
+				frame.Lineno = n
+			}
+		}
+		frames = append(frames, frame)
+	}
+
+	if len(frames) == 0 {
+		return false
+	}
+
+	// pkgerrors.MarshalStack lists the innermost call first; Sentry expects
+	// frames ordered outermost-first.
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+
+	stacktrace := &sentry.Stacktrace{Frames: frames}
+
+	if len(event.Exception) > 0 {
+		event.Exception[0].Stacktrace = stacktrace
+	} else {
+		event.Threads = append(event.Threads, sentry.Thread{Stacktrace: stacktrace, Current: true})
+	}
+
+	return true
+}
+
+// WithLevels restricts the zerolog levels that are forwarded to Sentry. By
+// default, Error, Fatal and Panic are forwarded.
+func WithLevels(levels ...zerolog.Level) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.levels = levelSet(levels)
+	}
+}
+
+// WithBreadcrumbs enables recording every forwarded event as a breadcrumb in
+// addition to capturing it.
+func WithBreadcrumbs() Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.withBreadcrumbs = true
+	}
+}
+
+// WithFlushTimeout overrides how long Close waits for hub.Flush to drain
+// buffered events before giving up. The default is defaultFlushTimeout.
+func WithFlushTimeout(timeout time.Duration) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.flushTimeout = timeout
+	}
+}
+
+// WithSampleRate sets sentry.ClientOptions.SampleRate.
+func WithSampleRate(rate float64) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.SampleRate = rate
+	}
+}
+
+// WithRelease sets sentry.ClientOptions.Release.
+func WithRelease(release string) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.Release = release
+	}
+}
+
+// WithEnvironment sets sentry.ClientOptions.Environment.
+func WithEnvironment(environment string) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.Environment = environment
+	}
+}
+
+// WithServerName sets sentry.ClientOptions.ServerName.
+func WithServerName(serverName string) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.ServerName = serverName
+	}
+}
+
+// WithIgnoreErrors sets sentry.ClientOptions.IgnoreErrors.
+func WithIgnoreErrors(ignoreErrors []string) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.IgnoreErrors = ignoreErrors
+	}
+}
+
+// WithDebug sets sentry.ClientOptions.Debug to true.
+func WithDebug() Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.Debug = true
+	}
+}
+
+// WithTracing sets sentry.ClientOptions.EnableTracing to true.
+func WithTracing() Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.EnableTracing = true
+	}
+}
+
+// WithTracingSampleRate sets sentry.ClientOptions.TracesSampleRate.
+func WithTracingSampleRate(rate float64) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.TracesSampleRate = rate
+	}
+}
+
+// WithBeforeSend sets sentry.ClientOptions.BeforeSend.
+func WithBeforeSend(fn func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.BeforeSend = fn
+	}
+}
+
+// WithDebugWriter sets sentry.ClientOptions.DebugWriter.
+func WithDebugWriter(writer io.Writer) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.DebugWriter = writer
+	}
+}
+
+// WithHttpClient sets sentry.ClientOptions.HTTPClient.
+func WithHttpClient(client *http.Client) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.HTTPClient = client
+	}
+}
+
+// WithHttpProxy sets sentry.ClientOptions.HTTPProxy.
+func WithHttpProxy(proxy string) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.HTTPProxy = proxy
+	}
+}
+
+// WithHttpsProxy sets sentry.ClientOptions.HTTPSProxy.
+func WithHttpsProxy(proxy string) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.HTTPSProxy = proxy
+	}
+}
+
+// WithCaCerts sets sentry.ClientOptions.CaCerts.
+func WithCaCerts(caCerts *x509.CertPool) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.CaCerts = caCerts
+	}
+}
+
+// WithMaxErrorDepth sets sentry.ClientOptions.MaxErrorDepth.
+func WithMaxErrorDepth(depth int) Option {
+	return func(_ *Writer, o *sentry.ClientOptions) {
+		o.MaxErrorDepth = depth
+	}
+}
+
+// WithTagFields routes the named zerolog fields to event.Tags instead of
+// event.Extra, making their values searchable and usable for alerting in
+// Sentry.
+func WithTagFields(names ...string) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		tagFields := make(map[string]struct{}, len(names))
+		for _, name := range names {
+			tagFields[name] = struct{}{}
+		}
+		w.tagFields = tagFields
+	}
+}
+
+// WithFingerprintField routes the named zerolog field to event.Fingerprint,
+// splitting its string value on fingerprintSeparator ("|") to build Sentry's
+// custom grouping key.
+func WithFingerprintField(name string) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.fingerprintField = name
+	}
+}
+
+// WithTransactionField routes the named zerolog field to event.Transaction.
+func WithTransactionField(name string) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.transactionField = name
+	}
+}
+
+// WithUserFields routes the named zerolog fields to event.User, so that
+// Sentry can group and search issues by the affected user. Pass an empty
+// string for any field that isn't present in the writer's log lines.
+func WithUserFields(idField, emailField, usernameField string) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.userIDField = idField
+		w.userEmailField = emailField
+		w.userUsernameField = usernameField
+	}
+}
+
+// WithStackField routes the named zerolog field, expected to hold the output
+// of zerolog/pkgerrors.MarshalStack, to the event's stacktrace.
+func WithStackField(name string) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.stackField = name
+	}
+}
+
+// WithTraceContext routes the named zerolog fields to
+// event.Contexts["trace"], so Sentry links the issue to the owning
+// transaction/span. Pass an empty string for any field that isn't logged:
+// parentSpanIDField when parent spans aren't logged, or opField/statusField
+// to omit the span's operation name and status (statusField is expected to
+// hold one of sentry.SpanStatus's string names, e.g. "ok" or "not_found";
+// an unrecognized value is dropped rather than rejecting the whole context).
+func WithTraceContext(traceIDField, spanIDField, parentSpanIDField, opField, statusField string) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.traceIDField = traceIDField
+		w.spanIDField = spanIDField
+		w.parentSpanIDField = parentSpanIDField
+		w.traceOpField = opField
+		w.traceStatusField = statusField
+	}
+}
+
+// WithTraceParentField routes the named zerolog field, expected to hold a
+// W3C "traceparent" header value, to event.Contexts["trace"]. When it holds a
+// valid traceparent, its trace and span IDs take precedence over
+// WithTraceContext's trace and span ID fields on the same log line; the
+// parent span ID, op and status fields still apply.
+func WithTraceParentField(name string) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.traceParentField = name
+	}
+}
+
+// AttachContext returns log with the current Sentry span's trace ID, span
+// ID, parent span ID, operation name and status attached as fields (named
+// DefaultTraceIDField, DefaultSpanIDField, DefaultParentSpanIDField,
+// DefaultTraceOpField and DefaultTraceStatusField), so that any event built
+// from the resulting logger's output can be linked back to the transaction
+// in ctx by a Writer configured with matching WithTraceContext field names.
+// log is returned unchanged if ctx carries no active span.
+func AttachContext(ctx context.Context, log zerolog.Logger) zerolog.Logger {
+	span := sentry.SpanFromContext(ctx)
+	if span == nil {
+		return log
+	}
+
+	lc := log.With().
+		Str(DefaultTraceIDField, span.TraceID.String()).
+		Str(DefaultSpanIDField, span.SpanID.String())
+
+	if span.ParentSpanID != (sentry.SpanID{}) {
+		lc = lc.Str(DefaultParentSpanIDField, span.ParentSpanID.String())
+	}
+
+	if span.Op != "" {
+		lc = lc.Str(DefaultTraceOpField, span.Op)
+	}
+
+	if span.Status != sentry.SpanStatusUndefined {
+		lc = lc.Str(DefaultTraceStatusField, span.Status.String())
+	}
+
+	return lc.Logger()
+}
+
+// WithEventProcessor registers an EventProcessor to run, in registration
+// order, on every event before it reaches the Sentry hub. It may be called
+// multiple times to build a processing pipeline (e.g. PII scrubbing, rate
+// limiting, sampling); see the processors subpackage for ready-made ones.
+func WithEventProcessor(processor EventProcessor) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.eventProcessors = append(w.eventProcessors, processor)
+	}
+}
+
+// WithAsync makes the writer hand parsed events off to a bounded queue
+// drained by a pool of workers instead of calling hub.CaptureEvent on the
+// caller's goroutine. This keeps Write/WriteLevel non-blocking even when the
+// Sentry transport is slow, at the cost of applying policy when the queue
+// fills up. Call Close to drain the queue and stop the workers.
+func WithAsync(queueSize, workers int, policy DropPolicy) Option {
+	return func(w *Writer, _ *sentry.ClientOptions) {
+		w.async = &asyncState{
+			queue:   make(chan *sentry.Event, queueSize),
+			policy:  policy,
+			workers: workers,
+		}
+	}
+}
+
+// startAsyncWorkers launches the worker pool once the writer's hub is ready.
+// It must run after hub assignment, since the workers call w.hub.CaptureEvent.
+func (w *Writer) startAsyncWorkers() {
+	if w.async == nil {
+		return
+	}
+
+	for i := 0; i < w.async.workers; i++ {
+		w.async.wg.Add(1)
+		go w.runAsyncWorker()
+	}
+}
@@ -1,11 +1,16 @@
 package zlogsentry
 
 import (
+	"bytes"
+	"context"
 	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"regexp"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +18,8 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/hentaiOS-Infrastructure/zerolog-sentry/processors"
 )
 
 var logEventJSON = []byte(`{"level":"error","requestId":"bee07485-2485-4f64-99e1-d10165884ca7","error":"dial timeout","time":"2020-06-25T17:19:00+03:00","test":"test","message":"test message"}`)
@@ -42,6 +49,405 @@ func TestParseLogEvent(t *testing.T) {
 	require.Len(t, ev.Extra, 2)
 	assert.Equal(t, "test", ev.Extra["test"])
 	assert.Equal(t, "bee07485-2485-4f64-99e1-d10165884ca7", ev.Extra["requestId"])
+
+	assert.Empty(t, ev.Tags)
+	assert.Empty(t, ev.Fingerprint)
+	assert.Empty(t, ev.Transaction)
+}
+
+func TestParseLogEvent_TagFields(t *testing.T) {
+	w, err := New("", WithTagFields("requestId", "test"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(logEventJSON)
+	require.True(t, ok)
+
+	assert.Equal(t, "bee07485-2485-4f64-99e1-d10165884ca7", ev.Tags["requestId"])
+	assert.Equal(t, "test", ev.Tags["test"])
+	assert.NotContains(t, ev.Extra, "requestId")
+	assert.NotContains(t, ev.Extra, "test")
+}
+
+func TestParseLogEvent_TagFields_Malformed(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","requestId":["a","b"]}`)
+	w, err := New("", WithTagFields("requestId"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.NotContains(t, ev.Tags, "requestId")
+	assert.Equal(t, `["a","b"]`, ev.Extra["requestId"])
+}
+
+func TestParseLogEvent_FingerprintField(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","fingerprint":"db|timeout"}`)
+	w, err := New("", WithFingerprintField("fingerprint"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.Equal(t, []string{"db", "timeout"}, ev.Fingerprint)
+	assert.NotContains(t, ev.Extra, "fingerprint")
+}
+
+func TestParseLogEvent_FingerprintField_Malformed(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","fingerprint":["db","timeout"]}`)
+	w, err := New("", WithFingerprintField("fingerprint"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.Empty(t, ev.Fingerprint)
+	assert.Equal(t, `["db","timeout"]`, ev.Extra["fingerprint"])
+}
+
+func TestParseLogEvent_TransactionField(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","txn":"POST /orders"}`)
+	w, err := New("", WithTransactionField("txn"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.Equal(t, "POST /orders", ev.Transaction)
+	assert.NotContains(t, ev.Extra, "txn")
+}
+
+func TestParseLogEvent_TransactionField_Malformed(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","txn":123}`)
+	w, err := New("", WithTransactionField("txn"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.Empty(t, ev.Transaction)
+	assert.Equal(t, "123", ev.Extra["txn"])
+}
+
+func TestParseLogEvent_UserFields(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","userId":42,"userEmail":"a@b.com","userName":"alice"}`)
+	w, err := New("", WithUserFields("userId", "userEmail", "userName"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.Equal(t, "42", ev.User.ID)
+	assert.Equal(t, "a@b.com", ev.User.Email)
+	assert.Equal(t, "alice", ev.User.Username)
+	assert.NotContains(t, ev.Extra, "userId")
+}
+
+func TestParseLogEvent_UserFields_Malformed(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","userId":{"nested":true},"userEmail":404}`)
+	w, err := New("", WithUserFields("userId", "userEmail", ""))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.Empty(t, ev.User.ID)
+	assert.Empty(t, ev.User.Email)
+	assert.Equal(t, `{"nested":true}`, ev.Extra["userId"])
+	assert.Equal(t, "404", ev.Extra["userEmail"])
+}
+
+func TestParseLogEvent_StackField(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","error":"dial timeout","stack":[{"func":"main.inner","source":"main.go","line":"10"},{"func":"main.outer","source":"main.go","line":"20"}]}`)
+	w, err := New("", WithStackField("stack"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	require.Len(t, ev.Exception, 1)
+	require.NotNil(t, ev.Exception[0].Stacktrace)
+	require.Len(t, ev.Exception[0].Stacktrace.Frames, 2)
+	assert.Equal(t, "main.outer", ev.Exception[0].Stacktrace.Frames[0].Function)
+	assert.Equal(t, "main.inner", ev.Exception[0].Stacktrace.Frames[1].Function)
+	assert.Equal(t, 20, ev.Exception[0].Stacktrace.Frames[0].Lineno)
+}
+
+func TestParseLogEvent_StackField_NoException(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","stack":[{"func":"main.inner","source":"main.go","line":"10"}]}`)
+	w, err := New("", WithStackField("stack"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	require.Empty(t, ev.Exception)
+	require.Len(t, ev.Threads, 1)
+	assert.True(t, ev.Threads[0].Current)
+	require.NotNil(t, ev.Threads[0].Stacktrace)
+	require.Len(t, ev.Threads[0].Stacktrace.Frames, 1)
+}
+
+func TestParseLogEvent_TraceContext(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","trace_id":"4c79f60c11214eb38604f4ae0781bfb2","span_id":"e1c1db5c1e918dbb","parent_id":"d1c1db5c1e918dbc"}`)
+	w, err := New("", WithTraceContext("trace_id", "span_id", "parent_id", "", ""))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	require.Contains(t, ev.Contexts, "trace")
+	trace := ev.Contexts["trace"]
+	assert.Equal(t, "4c79f60c11214eb38604f4ae0781bfb2", trace["trace_id"])
+	assert.Equal(t, "e1c1db5c1e918dbb", trace["span_id"])
+	assert.Equal(t, "d1c1db5c1e918dbc", trace["parent_span_id"])
+	assert.NotContains(t, trace, "op")
+	assert.NotContains(t, trace, "status")
+}
+
+func TestParseLogEvent_TraceContext_OpAndStatus(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","trace_id":"4c79f60c11214eb38604f4ae0781bfb2","span_id":"e1c1db5c1e918dbb","op":"http.server","status":"not_found"}`)
+	w, err := New("", WithTraceContext("trace_id", "span_id", "", "op", "status"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	require.Contains(t, ev.Contexts, "trace")
+	trace := ev.Contexts["trace"]
+	assert.Equal(t, "http.server", trace["op"])
+	assert.Equal(t, "not_found", trace["status"])
+}
+
+func TestParseLogEvent_TraceContext_UnknownStatus(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","trace_id":"4c79f60c11214eb38604f4ae0781bfb2","span_id":"e1c1db5c1e918dbb","status":"not-a-real-status"}`)
+	w, err := New("", WithTraceContext("trace_id", "span_id", "", "", "status"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	require.Contains(t, ev.Contexts, "trace")
+	assert.NotContains(t, ev.Contexts["trace"], "status")
+}
+
+func TestParseLogEvent_TraceContext_WrongType(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","trace_id":12345}`)
+	w, err := New("", WithTraceContext("trace_id", "span_id", "parent_id", "", ""))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.NotContains(t, ev.Contexts, "trace")
+	assert.Equal(t, "12345", ev.Extra["trace_id"])
+}
+
+func TestParseLogEvent_TraceParentField(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","traceparent":"00-4c79f60c11214eb38604f4ae0781bfb2-e1c1db5c1e918dbb-01"}`)
+	w, err := New("", WithTraceParentField("traceparent"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	require.Contains(t, ev.Contexts, "trace")
+	trace := ev.Contexts["trace"]
+	assert.Equal(t, "4c79f60c11214eb38604f4ae0781bfb2", trace["trace_id"])
+	assert.Equal(t, "e1c1db5c1e918dbb", trace["span_id"])
+}
+
+func TestParseLogEvent_TraceParentField_Malformed(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","traceparent":"not-a-traceparent"}`)
+	w, err := New("", WithTraceParentField("traceparent"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.NotContains(t, ev.Contexts, "trace")
+}
+
+func TestParseLogEvent_TraceParentField_WrongType(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","traceparent":true}`)
+	w, err := New("", WithTraceParentField("traceparent"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.NotContains(t, ev.Contexts, "trace")
+	assert.Equal(t, "true", ev.Extra["traceparent"])
+}
+
+func TestAttachContext_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	span := sentry.StartSpan(context.Background(), "test-op")
+	defer span.Finish()
+
+	log := AttachContext(span.Context(), zerolog.New(&buf))
+	log.Error().Msg("within span")
+
+	var fields map[string]interface{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &fields))
+
+	assert.Equal(t, span.TraceID.String(), fields[DefaultTraceIDField])
+	assert.Equal(t, span.SpanID.String(), fields[DefaultSpanIDField])
+	assert.Equal(t, span.Op, fields[DefaultTraceOpField])
+
+	w, err := New("", WithTraceContext(
+		DefaultTraceIDField, DefaultSpanIDField, DefaultParentSpanIDField,
+		DefaultTraceOpField, DefaultTraceStatusField,
+	))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(buf.Bytes())
+	require.True(t, ok)
+
+	require.Contains(t, ev.Contexts, "trace")
+	trace := ev.Contexts["trace"]
+	assert.Equal(t, span.TraceID.String(), trace["trace_id"])
+	assert.Equal(t, span.SpanID.String(), trace["span_id"])
+	assert.Equal(t, span.Op, trace["op"])
+	assert.NotContains(t, trace, "status", "a span with the default SpanStatusUndefined should not set a status")
+}
+
+func TestAttachContext_NoSpan(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := AttachContext(context.Background(), zerolog.New(&buf))
+	log.Error().Msg("no span")
+
+	var fields map[string]interface{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &fields))
+
+	assert.NotContains(t, fields, DefaultTraceIDField)
+}
+
+func TestWriteLevel_EventProcessorOrdering(t *testing.T) {
+	var order []string
+
+	writer, err := New("",
+		WithEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			order = append(order, "first")
+			return event
+		}),
+		WithEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			order = append(order, "second")
+			return event
+		}),
+	)
+	require.Nil(t, err)
+
+	_, err = writer.WriteLevel(zerolog.ErrorLevel, logEventJSON)
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{"first", "second"}, order)
+}
+
+func TestWriteLevel_EventProcessorSuppressesEvent(t *testing.T) {
+	beforeSendCalled := false
+	secondCalled := false
+
+	writer, err := New("",
+		WithEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			return nil
+		}),
+		WithEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			secondCalled = true
+			return event
+		}),
+		WithBeforeSend(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			beforeSendCalled = true
+			return event
+		}),
+	)
+	require.Nil(t, err)
+
+	var zerologError error
+	zerolog.ErrorHandler = func(err error) {
+		zerologError = err
+	}
+
+	n, err := writer.WriteLevel(zerolog.ErrorLevel, logEventJSON)
+
+	assert.Equal(t, len(logEventJSON), n)
+	assert.Nil(t, err)
+	assert.Nil(t, zerologError)
+	assert.False(t, secondCalled, "processors after a nil result should not run")
+	assert.False(t, beforeSendCalled, "a suppressed event should never reach the hub's BeforeSend")
+}
+
+func TestWriteLevel_WithBuiltinScrubProcessor(t *testing.T) {
+	var capturedMessage string
+
+	writer, err := New("",
+		WithEventProcessor(processors.Scrub(regexp.MustCompile(`dial \w+`), "[redacted]")),
+		WithBeforeSend(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			capturedMessage = event.Message
+			return event
+		}),
+	)
+	require.Nil(t, err)
+
+	data := []byte(`{"level":"error","message":"dial timeout while connecting"}`)
+	_, err = writer.WriteLevel(zerolog.ErrorLevel, data)
+	require.Nil(t, err)
+
+	assert.Equal(t, "[redacted] while connecting", capturedMessage)
+}
+
+func TestWriteLevel_WithBuiltinFingerprintLimiter(t *testing.T) {
+	var captured int
+
+	limiter := processors.NewFingerprintLimiter(0, 1)
+	writer, err := New("",
+		WithEventProcessor(limiter.Process),
+		WithBeforeSend(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			captured++
+			return event
+		}),
+	)
+	require.Nil(t, err)
+
+	data := []byte(`{"level":"error","message":"dial timeout"}`)
+	_, err = writer.WriteLevel(zerolog.ErrorLevel, data)
+	require.Nil(t, err)
+	_, err = writer.WriteLevel(zerolog.ErrorLevel, data)
+	require.Nil(t, err)
+
+	assert.Equal(t, 1, captured, "the second identical event should be rate-limited by the shared fingerprint bucket")
+}
+
+func TestWriteLevel_WithBuiltinLevelSampler(t *testing.T) {
+	var captured int
+
+	sampler := processors.NewLevelSampler(map[sentry.Level]float64{sentry.LevelError: 0})
+	writer, err := New("",
+		WithEventProcessor(sampler.Process),
+		WithBeforeSend(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			captured++
+			return event
+		}),
+	)
+	require.Nil(t, err)
+
+	_, err = writer.WriteLevel(zerolog.ErrorLevel, logEventJSON)
+	require.Nil(t, err)
+
+	assert.Equal(t, 0, captured, "a zero-rate level sampler should suppress the event via WithEventProcessor")
+}
+
+func TestParseLogEvent_StackField_Malformed(t *testing.T) {
+	data := []byte(`{"level":"error","message":"boom","stack":"not-a-list"}`)
+	w, err := New("", WithStackField("stack"))
+	require.Nil(t, err)
+
+	ev, ok := w.parseLogEvent(data)
+	require.True(t, ok)
+
+	assert.Empty(t, ev.Exception)
+	assert.Empty(t, ev.Threads)
 }
 
 func TestParseLogLevel(t *testing.T) {
@@ -292,6 +698,14 @@ func TestNewWithHub(t *testing.T) {
 	assert.NotNil(t, writer)
 }
 
+func TestNewWithHub_WithOptions(t *testing.T) {
+	hub := sentry.NewHub(nil, nil)
+	writer, err := NewWithHub(hub, WithFlushTimeout(10*time.Second))
+	require.Nil(t, err)
+
+	assert.Equal(t, 10*time.Second, writer.flushTimeout)
+}
+
 func TestNewWithHub_NilHub(t *testing.T) {
 	_, err := NewWithHub(nil)
 	assert.NotNil(t, err)
@@ -362,9 +776,6 @@ func TestAddBreadcrumb(t *testing.T) {
 	writer, err := New("", WithBreadcrumbs(), WithBeforeSend(beforeSend))
 	require.Nil(t, err)
 
-	hub := sentry.CurrentHub().Clone()
-	writer.hub = hub
-
 	event := &sentry.Event{
 		Level:   sentry.LevelError,
 		Message: "Breadcrumb test",
@@ -375,7 +786,7 @@ func TestAddBreadcrumb(t *testing.T) {
 	writer.addBreadcrumb(event)
 
 	// Simulate capturing an event that would include breadcrumbs
-	hub.CaptureMessage("trigger breadcrumbs")
+	writer.hub.CaptureMessage("trigger breadcrumbs")
 
 	// Ensure the event was captured through beforeSend callback
 	require.NotNil(t, capturedEvent)
@@ -401,6 +812,20 @@ func TestWithLevels(t *testing.T) {
 	assert.Equal(t, expectedLevels, writer.levels)
 }
 
+func TestWithFlushTimeout(t *testing.T) {
+	writer, err := New("", WithFlushTimeout(10*time.Second))
+	require.Nil(t, err)
+
+	assert.Equal(t, 10*time.Second, writer.flushTimeout)
+}
+
+func TestWithFlushTimeout_Default(t *testing.T) {
+	writer, err := New("")
+	require.Nil(t, err)
+
+	assert.Equal(t, defaultFlushTimeout, writer.flushTimeout)
+}
+
 func TestWithSampleRate(t *testing.T) {
 	sampleRate := 0.5
 	writer, err := New("", WithSampleRate(sampleRate))
@@ -538,3 +963,148 @@ type testWriter struct{}
 func (t *testWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
+
+// blockingRoundTripper simulates a stalled Sentry transport: every request
+// sleeps for delay before failing, which keeps the transport's background
+// sender stuck on each request while its buffer backs up.
+type blockingRoundTripper struct {
+	delay time.Duration
+}
+
+func (t *blockingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	time.Sleep(t.delay)
+	return nil, errors.New("blocked transport")
+}
+
+func TestWithAsync_DropNewest(t *testing.T) {
+	writer, err := New("https://public@sentry.example.com/1",
+		WithHttpClient(&http.Client{Transport: &blockingRoundTripper{delay: time.Hour}}),
+		WithAsync(1, 1, DropNewest))
+	require.Nil(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, _ = writer.Write(logEventJSON)
+	}
+
+	stats := writer.AsyncStats()
+	assert.Equal(t, uint64(10), stats.Enqueued+stats.Dropped)
+	assert.True(t, stats.Dropped > 0, "expected some events to be dropped once the queue filled up")
+}
+
+func TestWithAsync_DropOldest(t *testing.T) {
+	writer, err := New("https://public@sentry.example.com/1",
+		WithHttpClient(&http.Client{Transport: &blockingRoundTripper{delay: time.Hour}}),
+		WithAsync(1, 1, DropOldest))
+	require.Nil(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, _ = writer.Write(logEventJSON)
+	}
+
+	stats := writer.AsyncStats()
+	assert.True(t, stats.Dropped > 0, "expected older events to be displaced once the queue filled up")
+}
+
+func TestWithAsync_CloseDuringConcurrentWrite(t *testing.T) {
+	writer, err := New("https://public@sentry.example.com/1",
+		WithHttpClient(&http.Client{Transport: &blockingRoundTripper{delay: time.Millisecond}}),
+		WithAsync(4, 2, DropNewest))
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_, _ = writer.Write(logEventJSON)
+			}
+		}
+	}()
+
+	// Give the writer goroutine a chance to start racing Close.
+	time.Sleep(time.Millisecond)
+
+	assert.NotPanics(t, func() {
+		require.Nil(t, writer.Close())
+	})
+
+	close(stop)
+	wg.Wait()
+}
+
+// p99 returns the 99th-percentile duration in samples. samples is sorted in
+// place.
+func p99(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	idx := int(float64(len(samples))*0.99) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(samples) {
+		idx = len(samples) - 1
+	}
+
+	return samples[idx]
+}
+
+// BenchmarkWriteLogEvent_BlockedTransport_Sync measures the p99 latency of
+// Write when every call has to wait on hub.CaptureEvent with a stalled HTTP
+// transport.
+func BenchmarkWriteLogEvent_BlockedTransport_Sync(b *testing.B) {
+	w, err := New("https://public@sentry.example.com/1",
+		WithHttpClient(&http.Client{Transport: &blockingRoundTripper{delay: 5 * time.Millisecond}}))
+	if err != nil {
+		b.Errorf("failed to create writer: %v", err)
+	}
+
+	samples := make([]time.Duration, b.N)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		_, _ = w.Write(logEventJSON)
+		samples[i] = time.Since(start)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(p99(samples).Nanoseconds()), "p99-ns/op")
+}
+
+// BenchmarkWriteLogEvent_BlockedTransport_Async measures the p99 latency of
+// Write under the same stalled transport with WithAsync enabled: Write
+// should return immediately regardless of transport delay. Note that
+// sentry's own HTTPTransport already buffers and sends asynchronously, so
+// the gap over the sync benchmark here reflects only the cost WithAsync
+// removes on top of that (Write no longer runs hub.CaptureEvent itself), not
+// the full cost of a synchronous HTTP round trip.
+func BenchmarkWriteLogEvent_BlockedTransport_Async(b *testing.B) {
+	w, err := New("https://public@sentry.example.com/1",
+		WithHttpClient(&http.Client{Transport: &blockingRoundTripper{delay: 5 * time.Millisecond}}),
+		WithAsync(1024, 4, DropNewest))
+	if err != nil {
+		b.Errorf("failed to create writer: %v", err)
+	}
+
+	samples := make([]time.Duration, b.N)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		_, _ = w.Write(logEventJSON)
+		samples[i] = time.Since(start)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(p99(samples).Nanoseconds()), "p99-ns/op")
+}